@@ -0,0 +1,27 @@
+// Package charmadapter adapts a charmbracelet/log.Logger to processbuilder.Logger.
+package charmadapter
+
+import (
+	charmlog "github.com/charmbracelet/log"
+
+	processbuilder "github.com/sephiroth74/go-processbuilder"
+)
+
+// Adapter wraps a charmbracelet/log.Logger to satisfy processbuilder.Logger.
+// charmbracelet/log has no trace level, so Tracef is logged at debug level.
+type Adapter struct {
+	Logger *charmlog.Logger
+}
+
+// New wraps l as a processbuilder.Logger.
+func New(l *charmlog.Logger) *Adapter {
+	return &Adapter{Logger: l}
+}
+
+func (a *Adapter) Tracef(format string, args ...interface{}) { a.Logger.Debugf(format, args...) }
+func (a *Adapter) Debugf(format string, args ...interface{}) { a.Logger.Debugf(format, args...) }
+func (a *Adapter) Infof(format string, args ...interface{})  { a.Logger.Infof(format, args...) }
+func (a *Adapter) Warnf(format string, args ...interface{})  { a.Logger.Warnf(format, args...) }
+func (a *Adapter) Errorf(format string, args ...interface{}) { a.Logger.Errorf(format, args...) }
+
+var _ processbuilder.Logger = (*Adapter)(nil)
@@ -0,0 +1,30 @@
+// Package stdlogadapter adapts the standard library's log.Logger to processbuilder.Logger.
+package stdlogadapter
+
+import (
+	stdlog "log"
+
+	processbuilder "github.com/sephiroth74/go-processbuilder"
+)
+
+// Adapter wraps a standard library *log.Logger to satisfy
+// processbuilder.Logger, prefixing each line with its level.
+type Adapter struct {
+	Logger *stdlog.Logger
+}
+
+// New wraps l as a processbuilder.Logger. A nil l uses log.Default().
+func New(l *stdlog.Logger) *Adapter {
+	if l == nil {
+		l = stdlog.Default()
+	}
+	return &Adapter{Logger: l}
+}
+
+func (a *Adapter) Tracef(format string, args ...interface{}) { a.Logger.Printf("TRACE "+format, args...) }
+func (a *Adapter) Debugf(format string, args ...interface{}) { a.Logger.Printf("DEBUG "+format, args...) }
+func (a *Adapter) Infof(format string, args ...interface{})  { a.Logger.Printf("INFO "+format, args...) }
+func (a *Adapter) Warnf(format string, args ...interface{})  { a.Logger.Printf("WARN "+format, args...) }
+func (a *Adapter) Errorf(format string, args ...interface{}) { a.Logger.Printf("ERROR "+format, args...) }
+
+var _ processbuilder.Logger = (*Adapter)(nil)
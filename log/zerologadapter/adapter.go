@@ -0,0 +1,26 @@
+// Package zerologadapter adapts a zerolog.Logger to processbuilder.Logger.
+package zerologadapter
+
+import (
+	"github.com/rs/zerolog"
+
+	processbuilder "github.com/sephiroth74/go-processbuilder"
+)
+
+// Adapter wraps a zerolog.Logger to satisfy processbuilder.Logger.
+type Adapter struct {
+	Logger zerolog.Logger
+}
+
+// New wraps l as a processbuilder.Logger.
+func New(l zerolog.Logger) *Adapter {
+	return &Adapter{Logger: l}
+}
+
+func (a *Adapter) Tracef(format string, args ...interface{}) { a.Logger.Trace().Msgf(format, args...) }
+func (a *Adapter) Debugf(format string, args ...interface{}) { a.Logger.Debug().Msgf(format, args...) }
+func (a *Adapter) Infof(format string, args ...interface{})  { a.Logger.Info().Msgf(format, args...) }
+func (a *Adapter) Warnf(format string, args ...interface{})  { a.Logger.Warn().Msgf(format, args...) }
+func (a *Adapter) Errorf(format string, args ...interface{}) { a.Logger.Error().Msgf(format, args...) }
+
+var _ processbuilder.Logger = (*Adapter)(nil)
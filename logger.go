@@ -0,0 +1,49 @@
+package processbuilder
+
+// LogLevel gates which of the package's internal trace messages reach the
+// configured Logger. Values line up with the usual trace < debug < info <
+// warn < error ordering so `p.option.LogLevel <= LogLevelDebug` reads
+// naturally.
+type LogLevel int
+
+const (
+	LogLevelTrace    LogLevel = -1
+	LogLevelDebug    LogLevel = 0
+	LogLevelInfo     LogLevel = 1
+	LogLevelWarn     LogLevel = 2
+	LogLevelError    LogLevel = 3
+	LogLevelDisabled LogLevel = 7
+)
+
+// Logger is the minimal logging interface the package needs to report
+// pipeline lifecycle events. It has no dependency on any particular logging
+// library, so consumers can plug in their own backend; see the
+// log/zerologadapter, log/charmadapter and log/stdlogadapter subpackages for
+// ready-made adapters.
+type Logger interface {
+	Tracef(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// noopLogger discards everything, so zero-config usage doesn't spam stdout.
+type noopLogger struct{}
+
+func (noopLogger) Tracef(string, ...interface{}) {}
+func (noopLogger) Debugf(string, ...interface{}) {}
+func (noopLogger) Infof(string, ...interface{})  {}
+func (noopLogger) Warnf(string, ...interface{})  {}
+func (noopLogger) Errorf(string, ...interface{}) {}
+
+var logger Logger = noopLogger{}
+
+// SetLogger installs the Logger used for the package's internal tracing. A
+// nil logger reverts to the default no-op logger.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = noopLogger{}
+	}
+	logger = l
+}
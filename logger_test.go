@@ -0,0 +1,42 @@
+package processbuilder
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+// recordingLogger counts the Debugf calls made to it, to verify the package
+// dispatches to whatever Logger is installed via SetLogger.
+type recordingLogger struct {
+	debugfCalls int
+}
+
+func (l *recordingLogger) Tracef(string, ...interface{}) {}
+func (l *recordingLogger) Debugf(string, ...interface{}) { l.debugfCalls++ }
+func (l *recordingLogger) Infof(string, ...interface{})  {}
+func (l *recordingLogger) Warnf(string, ...interface{})  {}
+func (l *recordingLogger) Errorf(string, ...interface{}) {}
+
+func TestSetLoggerDispatchesToInstalledLogger(t *testing.T) {
+	defer SetLogger(nil)
+
+	rec := &recordingLogger{}
+	SetLogger(rec)
+
+	_, _, code, _, err := Output(
+		Option{LogLevel: LogLevelDebug},
+		NewCommand("true"),
+	)
+
+	assert.NilError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Assert(t, rec.debugfCalls > 0, "expected the installed Logger to receive at least one Debugf call")
+}
+
+func TestSetLoggerNilRevertsToNoop(t *testing.T) {
+	SetLogger(&recordingLogger{})
+	SetLogger(nil)
+
+	assert.Equal(t, noopLogger{}, logger)
+}
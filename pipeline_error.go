@@ -0,0 +1,86 @@
+package processbuilder
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// defaultTailBufferSize is the number of bytes kept at the head and tail of
+// a command's captured stdout/stderr when no Option.TailBufferSize is set.
+const defaultTailBufferSize = 4096
+
+// TailSample is a head+tail excerpt of a captured stream, each capped at the
+// pipeline's configured tail buffer size. See ringWriter.
+type TailSample struct {
+	Head string
+	Tail string
+}
+
+// PipelineError is returned by Run/Wait/Output when one of the pipeline's
+// commands exits with a non-zero status or a signal. Borrowed from the idea
+// behind vanadium gosh's cmd log, it carries enough context - which command
+// failed, its argv, exit code/signal, and a head+tail excerpt of its
+// captured stdout/stderr - to diagnose a multi-stage pipeline without the
+// caller having to buffer everything itself.
+type PipelineError struct {
+	CmdIndex int
+	Argv     string
+	ExitCode int
+	Signaled bool
+	Signal   string
+	Stdout   TailSample
+	Stderr   TailSample
+	Err      error
+}
+
+func (e *PipelineError) Error() string {
+	status := fmt.Sprintf("exited %d", e.ExitCode)
+	if e.Signaled {
+		status = fmt.Sprintf("killed by signal %s", e.Signal)
+	}
+
+	return fmt.Sprintf(
+		"cmd[%d] '%s' %s: stdout head=%q, tail=%q, stderr head=%q, tail=%q",
+		e.CmdIndex, e.Argv, status,
+		e.Stdout.Head, e.Stdout.Tail,
+		e.Stderr.Head, e.Stderr.Tail,
+	)
+}
+
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+// ringWriter captures up to limit bytes from the start of a stream and the
+// last limit bytes, discarding everything in between. It never buffers the
+// full stream, so it is safe to attach to long-running pipelines.
+type ringWriter struct {
+	limit int
+	head  bytes.Buffer
+	tail  []byte
+}
+
+func newRingWriter(limit int) *ringWriter {
+	return &ringWriter{limit: limit}
+}
+
+func (r *ringWriter) Write(p []byte) (int, error) {
+	if remaining := r.limit - r.head.Len(); remaining > 0 {
+		if remaining > len(p) {
+			r.head.Write(p)
+		} else {
+			r.head.Write(p[:remaining])
+		}
+	}
+
+	r.tail = append(r.tail, p...)
+	if len(r.tail) > r.limit {
+		r.tail = r.tail[len(r.tail)-r.limit:]
+	}
+
+	return len(p), nil
+}
+
+func (r *ringWriter) sample() TailSample {
+	return TailSample{Head: r.head.String(), Tail: string(r.tail)}
+}
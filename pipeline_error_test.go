@@ -0,0 +1,76 @@
+package processbuilder
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestPipelineErrorCapturesStdoutStderrTail(t *testing.T) {
+	_, _, code, _, err := Output(
+		EmptyOption(),
+		NewCommand("sh", "-c", "echo out-line; echo err-line 1>&2; exit 3"),
+	)
+
+	assert.Equal(t, 3, code)
+	assert.ErrorType(t, err, (*PipelineError)(nil))
+
+	pipelineErr := err.(*PipelineError)
+	assert.Equal(t, 3, pipelineErr.ExitCode)
+	assert.Equal(t, false, pipelineErr.Signaled)
+	assert.Equal(t, "out-line\n", pipelineErr.Stdout.Head)
+	assert.Equal(t, "err-line\n", pipelineErr.Stderr.Head)
+}
+
+// TestPipeOutputStderrSmallWriteDoesNotDeadlock is a regression test: prepare()
+// used to route the last stage's stderr through a raw io.Pipe when
+// PipeOutput was used, which blocks on the very first write until a caller
+// drains p.StdErrPipe. Wait must be able to reap the command even if nobody
+// ever reads p.StdErrPipe, as long as the write fits in the OS pipe buffer.
+func TestPipeOutputStderrSmallWriteDoesNotDeadlock(t *testing.T) {
+	p, err := PipeOutput(
+		EmptyOption(),
+		NewCommand("sh", "-c", "echo oops 1>&2"),
+	)
+	assert.NilError(t, err)
+	assert.NilError(t, Start(p))
+
+	done := make(chan struct{})
+	go func() {
+		Wait(p)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait did not return: stderr pipe deadlocked on a small write")
+	}
+}
+
+// TestPipeOutputStdoutSmallWriteDoesNotDeadlock mirrors
+// TestPipeOutputStderrSmallWriteDoesNotDeadlock for p.StdoutPipe, which had
+// the identical bug: the last stage's stdout was routed through a raw
+// io.Pipe when PipeOutput was used, deadlocking Wait on the very first
+// write until a caller drained p.StdoutPipe.
+func TestPipeOutputStdoutSmallWriteDoesNotDeadlock(t *testing.T) {
+	p, err := PipeOutput(
+		EmptyOption(),
+		NewCommand("sh", "-c", "echo oops"),
+	)
+	assert.NilError(t, err)
+	assert.NilError(t, Start(p))
+
+	done := make(chan struct{})
+	go func() {
+		Wait(p)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait did not return: stdout pipe deadlocked on a small write")
+	}
+}
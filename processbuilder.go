@@ -14,17 +14,13 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/rs/zerolog"
-
 	streams "github.com/sephiroth74/go_streams"
 )
 
 var (
-	consoleWriter         zerolog.ConsoleWriter = zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC822Z}
-	Logger                *zerolog.Logger       = nil
-	ErrNoCommands         error                 = errors.New("at least one command is required")
-	ErrProcAlreadyStarted error                 = errors.New("process already started")
-	ErrProcNotStarted     error                 = errors.New("process not started")
+	ErrNoCommands         error = errors.New("at least one command is required")
+	ErrProcAlreadyStarted error = errors.New("process already started")
+	ErrProcNotStarted     error = errors.New("process not started")
 )
 
 type ExitStatus string
@@ -36,11 +32,6 @@ const (
 	ExitStatusStopped  ExitStatus = "stopped"
 )
 
-func init() {
-	defaultLogger := zerolog.New(consoleWriter).Level(zerolog.TraceLevel)
-	Logger = &defaultLogger
-}
-
 func getExitCode(cmd *exec.Cmd, err error) int {
 	code := cmd.ProcessState.ExitCode()
 	if e2, ok := err.(*exec.ExitError); ok {
@@ -55,8 +46,30 @@ func getExitCode(cmd *exec.Cmd, err error) int {
 	return code
 }
 
-func SetLogger(logger *zerolog.Logger) {
-	Logger = logger
+// newPipelineError builds a PipelineError describing why command at index
+// failed, sampling the head+tail of its captured stdout/stderr. exitCode is
+// the caller's already-computed exit code (see getExitCode), passed in
+// rather than recomputed so it can't disagree with what Run/Wait returns.
+func newPipelineError(index int, command *Command, exitCode int, err error) *PipelineError {
+	signaled := false
+	sig := ""
+	if e2, ok := err.(*exec.ExitError); ok {
+		if s, ok := e2.Sys().(syscall.WaitStatus); ok && s.Signaled() {
+			signaled = true
+			sig = s.Signal().String()
+		}
+	}
+
+	return &PipelineError{
+		CmdIndex: index,
+		Argv:     command.String(),
+		ExitCode: exitCode,
+		Signaled: signaled,
+		Signal:   sig,
+		Stdout:   command.ringOut.sample(),
+		Stderr:   command.ringErr.sample(),
+		Err:      err,
+	}
 }
 
 type Command struct {
@@ -67,16 +80,101 @@ type Command struct {
 	StdErr io.Writer
 	StdIn  io.Reader
 
+	env        map[string]string
+	envReplace bool
+	dir        string
+	credential *syscall.Credential
+
+	// tees are extra stdout writers attached via Processbuilder.Tee, on top
+	// of whatever the stage already writes to.
+	tees []io.Writer
+	// internalPipes are io.PipeWriters owned by internal helpers (e.g.
+	// Lines) that must be closed once this stage exits so their readers see EOF.
+	internalPipes []*io.PipeWriter
+
 	cmd        *exec.Cmd
 	pipeReader *io.PipeReader
 	pipeWriter *io.PipeWriter
+	ringOut    *ringWriter
+	ringErr    *ringWriter
 	exitCode   int
 }
 
+// teeReadCloser pairs a tee'd Reader with the Closer of the underlying
+// stream it wraps. Used to populate a ring buffer from the read side of an
+// OS-buffered pipe (e.g. exec.Cmd.StderrPipe), since the write side is
+// already claimed by the child process.
+type teeReadCloser struct {
+	r io.Reader
+	c io.Closer
+}
+
+func (t *teeReadCloser) Read(p []byte) (int, error) { return t.r.Read(p) }
+func (t *teeReadCloser) Close() error               { return t.c.Close() }
+
+// stdoutWriters combines base (the stage's normal stdout destinations) with
+// any tees attached via Processbuilder.Tee into a single io.Writer.
+func (c *Command) stdoutWriters(base ...io.Writer) io.Writer {
+	writers := append(append([]io.Writer{}, base...), c.tees...)
+	if len(writers) == 1 {
+		return writers[0]
+	}
+	return io.MultiWriter(writers...)
+}
+
 type Option struct {
 	Timeout    time.Duration
-	LogLevel   zerolog.Level
+	LogLevel   LogLevel
 	stdoutPipe bool
+
+	// Env, when set, is merged onto os.Environ() for every command in the
+	// pipeline. Per-command WithEnv/WithEnvAppend take precedence.
+	Env map[string]string
+	// Dir, when set, is the default working directory for every command in
+	// the pipeline. A per-command WithDir overrides it.
+	Dir string
+
+	// TailBufferSize is the number of bytes of stdout/stderr kept at the
+	// head and tail of each command for PipelineError. Defaults to 4KB.
+	TailBufferSize int
+}
+
+// mergeEnv returns a copy of base with any KEY=value pair whose key is
+// present in overrides replaced, appending keys that were not already there.
+func mergeEnv(base []string, overrides map[string]string) []string {
+	if len(overrides) == 0 {
+		return base
+	}
+
+	result := make([]string, 0, len(base)+len(overrides))
+	seen := make(map[string]bool, len(overrides))
+
+	for _, kv := range base {
+		key := strings.SplitN(kv, "=", 2)[0]
+		if v, ok := overrides[key]; ok {
+			result = append(result, key+"="+v)
+			seen[key] = true
+		} else {
+			result = append(result, kv)
+		}
+	}
+
+	for k, v := range overrides {
+		if !seen[k] {
+			result = append(result, k+"="+v)
+		}
+	}
+
+	return result
+}
+
+// envToSlice converts an env map to the "KEY=value" slice form exec.Cmd.Env expects.
+func envToSlice(env map[string]string) []string {
+	result := make([]string, 0, len(env))
+	for k, v := range env {
+		result = append(result, k+"="+v)
+	}
+	return result
 }
 
 func EmptyOption() Option {
@@ -94,6 +192,32 @@ type Processbuilder struct {
 	Ctx        context.Context
 	StdoutPipe io.ReadCloser
 	StdErrPipe io.ReadCloser
+
+	signalHandlers map[os.Signal][]func(*Processbuilder)
+	shutdownHooks  []func(cmd *Command, phase string)
+}
+
+// OnSignal registers handler to be invoked whenever sig is received while
+// the pipeline is running via Run or Wait. Run/Wait catch only the signals
+// that have handlers registered and dispatch them to those handlers; with no
+// handlers registered they leave signal handling untouched.
+func (p *Processbuilder) OnSignal(sig os.Signal, handler func(p *Processbuilder)) {
+	if p.signalHandlers == nil {
+		p.signalHandlers = make(map[os.Signal][]func(*Processbuilder))
+	}
+	p.signalHandlers[sig] = append(p.signalHandlers[sig], handler)
+}
+
+// OnShutdown registers hook to be invoked by Shutdown for every command as
+// it moves through the graceful-stop phases ("signal", "kill", "stopped").
+func (p *Processbuilder) OnShutdown(hook func(cmd *Command, phase string)) {
+	p.shutdownHooks = append(p.shutdownHooks, hook)
+}
+
+func (p *Processbuilder) notifyShutdown(cmd *Command, phase string) {
+	for _, hook := range p.shutdownHooks {
+		hook(cmd, phase)
+	}
 }
 
 func (p *Processbuilder) String() string {
@@ -111,6 +235,46 @@ func (p *Processbuilder) GetCmd(index int) *exec.Cmd {
 	return p.cmds[index].cmd
 }
 
+// Tee attaches an additional writer to stage index's stdout, without
+// disturbing the pipe chain to the next stage or the final destination of
+// the last stage. It re-runs prepare(), so it must be called before Start.
+func (p *Processbuilder) Tee(index int, w io.Writer) error {
+	if index < 0 || index >= len(p.cmds) {
+		return fmt.Errorf("tee: command index %d out of range", index)
+	}
+
+	p.cmds[index].tees = append(p.cmds[index].tees, w)
+	_, err := p.prepare()
+	return err
+}
+
+// watchSignals dispatches OS signals to handlers registered via OnSignal for
+// as long as the pipeline runs. With no handlers registered it does nothing,
+// rather than silently swallowing the signal as earlier versions did.
+func (p *Processbuilder) watchSignals() (stop func()) {
+	if len(p.signalHandlers) == 0 {
+		return func() {}
+	}
+
+	sigs := make([]os.Signal, 0, len(p.signalHandlers))
+	for sig := range p.signalHandlers {
+		sigs = append(sigs, sig)
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	go func() {
+		for sig := range ch {
+			for _, handler := range p.signalHandlers[sig] {
+				handler(p)
+			}
+		}
+	}()
+
+	return func() { signal.Stop(ch) }
+}
+
 func (p *Processbuilder) close() {
 	p.exited = true
 	for _, command := range p.cmds {
@@ -129,8 +293,8 @@ func (p *Processbuilder) prepare() (*Processbuilder, error) {
 		return data.String()
 	})
 
-	if Logger != nil && p.option.LogLevel <= zerolog.DebugLevel {
-		Logger.Debug().Msgf("Executing `%s`", strings.Join(cmds, " | "))
+	if p.option.LogLevel <= LogLevelDebug {
+		logger.Debugf("Executing `%s`", strings.Join(cmds, " | "))
 	}
 
 	var cancel context.CancelFunc
@@ -146,10 +310,15 @@ func (p *Processbuilder) prepare() (*Processbuilder, error) {
 	p.cancelFn = cancel
 	var previousCommand *Command
 
+	tailSize := p.option.TailBufferSize
+	if tailSize <= 0 {
+		tailSize = defaultTailBufferSize
+	}
+
 	// prepare commands
 	for index, command := range p.cmds {
-		if Logger != nil && p.option.LogLevel <= zerolog.TraceLevel {
-			Logger.Trace().Msgf("%d/%d preparing %s", index, total, command.String())
+		if p.option.LogLevel <= LogLevelTrace {
+			logger.Tracef("%d/%d preparing %s", index, total, command.String())
 		}
 
 		command.cmd = exec.CommandContext(ctx, command.command, command.args...)
@@ -165,8 +334,74 @@ func (p *Processbuilder) prepare() (*Processbuilder, error) {
 			return nil, errors.New("stdout allowed only for the last command")
 		}
 
-		if command.StdErr != nil {
-			command.cmd.Stderr = command.StdErr
+		command.ringOut = newRingWriter(tailSize)
+		command.ringErr = newRingWriter(tailSize)
+
+		// stderr is always tee'd into a ring buffer so a failing command can
+		// report a head+tail excerpt in its PipelineError, on top of
+		// whatever destination the caller requested
+		if index == total-1 && p.option.stdoutPipe {
+			errReader, pipeErr := command.cmd.StderrPipe()
+			if pipeErr != nil {
+				return nil, pipeErr
+			}
+			if command.StdErr != nil {
+				// command.StdErr may already carry extra destinations merged
+				// in by Lines()/WithStdErr. cmd.Stderr can't be a MultiWriter
+				// here since StderrPipe already claimed it, so pump the OS
+				// pipe into the ring buffer and those destinations from a
+				// goroutine, forwarding a copy to p.StdErrPipe for callers
+				// that also want to drain it directly.
+				outReader, outWriter := io.Pipe()
+				dest := io.MultiWriter(outWriter, command.ringErr, command.StdErr)
+				go func(src io.Reader, dst io.Writer, w *io.PipeWriter) {
+					_, copyErr := io.Copy(dst, src)
+					w.CloseWithError(copyErr)
+				}(errReader, dest, outWriter)
+				p.StdErrPipe = outReader
+			} else {
+				// cmd.StderrPipe is backed by an OS pipe with its own kernel
+				// buffer, so a caller that doesn't drain p.StdErrPipe right
+				// away doesn't deadlock the child on its very first write,
+				// unlike a raw io.Pipe. The ring buffer is filled on the
+				// read side instead.
+				p.StdErrPipe = &teeReadCloser{r: io.TeeReader(errReader, command.ringErr), c: errReader}
+			}
+		} else if command.StdErr != nil {
+			command.cmd.Stderr = io.MultiWriter(command.StdErr, command.ringErr)
+		} else {
+			command.cmd.Stderr = command.ringErr
+		}
+
+		// working directory: command-level overrides the pipeline default
+		dir := p.option.Dir
+		if command.dir != "" {
+			dir = command.dir
+		}
+		command.cmd.Dir = dir
+
+		// environment: pipeline-level default merged onto os.Environ(), then
+		// command-level WithEnv (replace) or WithEnvAppend (merge) on top
+		base := os.Environ()
+		if len(p.option.Env) > 0 {
+			base = mergeEnv(base, p.option.Env)
+		}
+		switch {
+		case command.env != nil && command.envReplace:
+			command.cmd.Env = envToSlice(command.env)
+		case command.env != nil:
+			command.cmd.Env = mergeEnv(base, command.env)
+		case len(p.option.Env) > 0:
+			command.cmd.Env = base
+		}
+
+		// each command gets its own process group so Shutdown can signal it
+		// (and any of its own children) as a unit
+		command.cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+		// run as a different user/group
+		if command.credential != nil {
+			command.cmd.SysProcAttr.Credential = command.credential
 		}
 
 		// first command
@@ -182,40 +417,55 @@ func (p *Processbuilder) prepare() (*Processbuilder, error) {
 			command.cmd.Stdin = previousCommand.pipeReader
 		}
 
-		// first .. second to last
-		if index < total-1 {
+		// stdout: chained into the next command's stdin for all but the last
+		// stage, and tee'd into a ring buffer (plus any Processbuilder.Tee
+		// writers) throughout for PipelineError/Lines
+		switch {
+		case index < total-1:
 			pipeReader, pipeWriter := io.Pipe()
-			command.pipeWriter = pipeWriter
 			command.pipeReader = pipeReader
-			command.cmd.Stdout = pipeWriter
-		}
-
-		// last command
-		if index == total-1 {
-			if p.option.stdoutPipe {
-				if Logger != nil && p.option.LogLevel <= zerolog.TraceLevel {
-					Logger.Trace().Msgf("using cmd.StdoutPipe on '%s'", command.String())
-				}
-				pipe, err := command.cmd.StdoutPipe()
-				if err != nil {
-					return nil, err
-				}
-				p.StdoutPipe = pipe
-
-				pipeErr, err := command.cmd.StderrPipe()
-				if err != nil {
-					return nil, err
-				}
-				p.StdErrPipe = pipeErr
+			command.pipeWriter = pipeWriter
+			command.cmd.Stdout = command.stdoutWriters(pipeWriter, command.ringOut)
 
+		case p.option.stdoutPipe:
+			if p.option.LogLevel <= LogLevelTrace {
+				logger.Tracef("using cmd.StdoutPipe on '%s'", command.String())
+			}
+			outReader, pipeErr := command.cmd.StdoutPipe()
+			if pipeErr != nil {
+				return nil, pipeErr
+			}
+			if len(command.tees) > 0 {
+				// command.tees may already carry extra destinations attached
+				// via Tee()/Lines(). cmd.Stdout can't be a MultiWriter here
+				// since StdoutPipe already claimed it, so pump the OS pipe
+				// into the ring buffer and those destinations from a
+				// goroutine, forwarding a copy to p.StdoutPipe for callers
+				// that also want to drain it directly.
+				teeReader, teeWriter := io.Pipe()
+				dest := command.stdoutWriters(teeWriter, command.ringOut)
+				go func(src io.Reader, dst io.Writer, w *io.PipeWriter) {
+					_, copyErr := io.Copy(dst, src)
+					w.CloseWithError(copyErr)
+				}(outReader, dest, teeWriter)
+				p.StdoutPipe = teeReader
 			} else {
-				if command.StdOut != nil {
-					if Logger != nil && p.option.LogLevel <= zerolog.TraceLevel {
-						Logger.Trace().Msgf("using cmd.StdOut on '%s'", command.String())
-					}
-					command.cmd.Stdout = command.StdOut
-				}
+				// cmd.StdoutPipe is backed by an OS pipe with its own kernel
+				// buffer, so a caller that doesn't drain p.StdoutPipe right
+				// away doesn't deadlock the child on its very first write,
+				// unlike a raw io.Pipe. The ring buffer is filled on the
+				// read side instead.
+				p.StdoutPipe = &teeReadCloser{r: io.TeeReader(outReader, command.ringOut), c: outReader}
+			}
+
+		case command.StdOut != nil:
+			if p.option.LogLevel <= LogLevelTrace {
+				logger.Tracef("using cmd.StdOut on '%s'", command.String())
 			}
+			command.cmd.Stdout = command.stdoutWriters(command.StdOut, command.ringOut)
+
+		default:
+			command.cmd.Stdout = command.stdoutWriters(command.ringOut)
 		}
 	}
 
@@ -285,8 +535,8 @@ func Start(p *Processbuilder) error {
 	total := len(p.cmds)
 
 	for index, command := range p.cmds {
-		if Logger != nil && p.option.LogLevel <= zerolog.TraceLevel {
-			Logger.Trace().Msgf("%d/%d calling start on command %s", index, total, command.String())
+		if p.option.LogLevel <= LogLevelTrace {
+			logger.Tracef("%d/%d calling start on command %s", index, total, command.String())
 		}
 
 		if err := command.cmd.Start(); err != nil {
@@ -310,22 +560,20 @@ func Run(p *Processbuilder) (int, *os.ProcessState, error) {
 
 	p.started = true
 
-	closeChannel := make(chan os.Signal, 1)
-	signal.Notify(closeChannel, os.Interrupt, syscall.SIGTERM)
-	defer signal.Stop(closeChannel)
-	defer close(closeChannel)
+	stopSignals := p.watchSignals()
+	defer stopSignals()
 
 	var previousCommand *Command
 	var lastCommand = p.cmds[total-1]
 
 	for index, command := range p.cmds {
-		if Logger != nil && p.option.LogLevel <= zerolog.TraceLevel {
-			Logger.Trace().Msgf("%d/%d calling run on command %s", index, total, command.String())
+		if p.option.LogLevel <= LogLevelTrace {
+			logger.Tracef("%d/%d calling run on command %s", index, total, command.String())
 		}
 
 		if err := command.cmd.Run(); err != nil {
-			if Logger != nil && p.option.LogLevel <= zerolog.TraceLevel {
-				Logger.Trace().Msgf("%d/%d run exited with error %s", index, total, err.Error())
+			if p.option.LogLevel <= LogLevelTrace {
+				logger.Tracef("%d/%d run exited with error %s", index, total, err.Error())
 			}
 
 			exitCode := getExitCode(command.cmd, err)
@@ -333,7 +581,7 @@ func Run(p *Processbuilder) (int, *os.ProcessState, error) {
 				exitCode = int(syscall.SIGINT)
 			}
 
-			return exitCode, command.cmd.ProcessState, err
+			return exitCode, command.cmd.ProcessState, newPipelineError(index, command, exitCode, err)
 		}
 
 		exitCode := command.cmd.ProcessState.ExitCode()
@@ -343,6 +591,10 @@ func Run(p *Processbuilder) (int, *os.ProcessState, error) {
 			command.pipeWriter.Close()
 		}
 
+		for _, w := range command.internalPipes {
+			w.Close()
+		}
+
 		if index > 0 {
 			previousCommand = p.cmds[index-1]
 			if previousCommand.pipeReader != nil {
@@ -364,28 +616,26 @@ func Wait(p *Processbuilder) (int, *os.ProcessState, error) {
 		return -1, nil, ErrProcNotStarted
 	}
 
-	closeChannel := make(chan os.Signal, 1)
-	signal.Notify(closeChannel, os.Interrupt, syscall.SIGTERM)
-	defer signal.Stop(closeChannel)
-	defer close(closeChannel)
+	stopSignals := p.watchSignals()
+	defer stopSignals()
 
 	var previousCommand *Command
 	var lastCommand = p.cmds[total-1]
 
 	for index, command := range p.cmds {
-		if Logger != nil && p.option.LogLevel <= zerolog.TraceLevel {
-			Logger.Trace().Msgf("%d/%d calling wait on command %s", index, total, command.String())
+		if p.option.LogLevel <= LogLevelTrace {
+			logger.Tracef("%d/%d calling wait on command %s", index, total, command.String())
 		}
 
 		if err := command.cmd.Wait(); err != nil {
-			if Logger != nil && p.option.LogLevel <= zerolog.TraceLevel {
-				Logger.Trace().Msgf("%d/%d wait exited with error %s", index, total, err.Error())
+			if p.option.LogLevel <= LogLevelTrace {
+				logger.Tracef("%d/%d wait exited with error %s", index, total, err.Error())
 			}
 			exitCode := getExitCode(command.cmd, err)
 			if p.killed {
 				exitCode = int(syscall.SIGINT)
 			}
-			return exitCode, command.cmd.ProcessState, err
+			return exitCode, command.cmd.ProcessState, newPipelineError(index, command, exitCode, err)
 		}
 
 		exitCode := command.cmd.ProcessState.ExitCode()
@@ -395,6 +645,10 @@ func Wait(p *Processbuilder) (int, *os.ProcessState, error) {
 			command.pipeWriter.Close()
 		}
 
+		for _, w := range command.internalPipes {
+			w.Close()
+		}
+
 		if index > 0 {
 			previousCommand = p.cmds[index-1]
 			if previousCommand.pipeReader != nil {
@@ -407,8 +661,8 @@ func Wait(p *Processbuilder) (int, *os.ProcessState, error) {
 }
 
 func Kill(p *Processbuilder) error {
-	if Logger != nil && p.option.LogLevel <= zerolog.DebugLevel {
-		Logger.Debug().Msg("Killing process...")
+	if p.option.LogLevel <= LogLevelDebug {
+		logger.Debugf("Killing process...")
 	}
 
 	p.killed = true
@@ -428,8 +682,8 @@ func Kill(p *Processbuilder) error {
 }
 
 func Cancel(p *Processbuilder) error {
-	if Logger != nil && p.option.LogLevel <= zerolog.DebugLevel {
-		Logger.Debug().Msgf("Cancelling process...")
+	if p.option.LogLevel <= LogLevelDebug {
+		logger.Debugf("Cancelling process...")
 	}
 
 	p.killed = true
@@ -467,6 +721,35 @@ func (c *Command) WithStdIn(r io.Reader) *Command {
 	return c
 }
 
+// WithEnv sets the command's environment to exactly the given vars, replacing
+// rather than inheriting the parent process environment.
+func (c *Command) WithEnv(env map[string]string) *Command {
+	c.env = env
+	c.envReplace = true
+	return c
+}
+
+// WithEnvAppend merges the given vars onto the inherited/default environment,
+// overriding any key already present.
+func (c *Command) WithEnvAppend(env map[string]string) *Command {
+	c.env = env
+	c.envReplace = false
+	return c
+}
+
+// WithDir sets the command's working directory, overriding the
+// Processbuilder-level default.
+func (c *Command) WithDir(dir string) *Command {
+	c.dir = dir
+	return c
+}
+
+// WithCredential runs the command as the given uid/gid via SysProcAttr.Credential.
+func (c *Command) WithCredential(uid, gid uint32) *Command {
+	c.credential = &syscall.Credential{Uid: uid, Gid: gid}
+	return c
+}
+
 func (c *Command) close() {
 	if c.pipeWriter != nil {
 		c.pipeWriter.Close()
@@ -474,6 +757,9 @@ func (c *Command) close() {
 	if c.pipeReader != nil {
 		c.pipeReader.Close()
 	}
+	for _, w := range c.internalPipes {
+		w.Close()
+	}
 }
 
 func (c *Command) String() string {
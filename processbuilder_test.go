@@ -200,3 +200,61 @@ func TestScreenRecord(t *testing.T) {
 
 	// shell screenrecord --bit-rate 20000000 --time-limit 180 /sdcard/Download/screenrecord.mp4
 }
+
+func TestWithDirOverridesWorkingDirectory(t *testing.T) {
+	outBuf, _, code, _, err := Output(
+		EmptyOption(),
+		NewCommand("pwd").WithDir("/tmp"),
+	)
+
+	assert.NilError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "/tmp\n", outBuf.String())
+}
+
+func TestWithEnvReplacesEnvironment(t *testing.T) {
+	outBuf, _, code, _, err := Output(
+		EmptyOption(),
+		NewCommand("env").WithEnv(map[string]string{"FOO": "bar"}),
+	)
+
+	assert.NilError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "FOO=bar\n", outBuf.String())
+}
+
+func TestWithEnvAppendMergesOntoInheritedEnvironment(t *testing.T) {
+	outBuf, _, code, _, err := Output(
+		EmptyOption(),
+		NewCommand("sh", "-c", "echo $PATH:$FOO").WithEnvAppend(map[string]string{"FOO": "baz"}),
+	)
+
+	assert.NilError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Assert(t, strings.Contains(outBuf.String(), ":baz"))
+	assert.Assert(t, strings.Contains(outBuf.String(), os.Getenv("PATH")))
+}
+
+func TestOptionEnvIsOverriddenByPerCommandEnv(t *testing.T) {
+	outBuf, _, code, _, err := Output(
+		Option{Env: map[string]string{"FOO": "pipeline"}},
+		NewCommand("sh", "-c", "echo $FOO").WithEnvAppend(map[string]string{"FOO": "command"}),
+	)
+
+	assert.NilError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "command\n", outBuf.String())
+}
+
+func TestWithCredentialSetsSysProcAttr(t *testing.T) {
+	p, err := Create(
+		EmptyOption(),
+		NewCommand("true").WithCredential(0, 0),
+	)
+	assert.NilError(t, err)
+
+	cmd := p.GetCmd(0)
+	assert.Assert(t, cmd.SysProcAttr.Credential != nil)
+	assert.Equal(t, uint32(0), cmd.SysProcAttr.Credential.Uid)
+	assert.Equal(t, uint32(0), cmd.SysProcAttr.Credential.Gid)
+}
@@ -0,0 +1,92 @@
+package processbuilder
+
+import (
+	"syscall"
+	"time"
+)
+
+// defaultShutdownTimeout is how long Shutdown waits for a pipeline to exit
+// after the initial signal before escalating to SIGKILL.
+const defaultShutdownTimeout = 5 * time.Second
+
+// GracefulOptions configures Shutdown.
+type GracefulOptions struct {
+	// Signal is sent to every command's process group first. Defaults to
+	// syscall.SIGTERM.
+	Signal syscall.Signal
+	// Timeout is how long to wait for the pipeline to exit before
+	// escalating to SIGKILL. Defaults to 5 seconds.
+	Timeout time.Duration
+}
+
+// Shutdown gracefully stops a started pipeline: it sends opts.Signal to
+// every command's process group (commands are started with
+// SysProcAttr.Setpgid so their own children are reached too), waits up to
+// opts.Timeout for exit, and escalates to SIGKILL on stragglers. Registered
+// OnShutdown hooks are invoked for each command as it moves through the
+// "signal", "kill" and "stopped" phases.
+func Shutdown(p *Processbuilder, opts GracefulOptions) error {
+	if !p.started || p.exited {
+		return ErrProcNotStarted
+	}
+
+	sig := opts.Signal
+	if sig == 0 {
+		sig = syscall.SIGTERM
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+
+	defer p.cancelFn()
+	defer p.close()
+
+	for _, command := range p.cmds {
+		if command.cmd.Process == nil {
+			continue
+		}
+		p.notifyShutdown(command, "signal")
+		syscall.Kill(-command.cmd.Process.Pid, sig)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for _, command := range p.cmds {
+			if command.cmd.Process == nil {
+				continue
+			}
+			// cmd.Wait, not Process.Wait: it also populates ProcessState
+			// and tears down the goroutines exec.Cmd uses to join
+			// stdout/stderr copying. The error is expected and discarded -
+			// SIGTERM/SIGKILL make this exit non-zero or signaled.
+			command.cmd.Wait()
+			if command.cmd.ProcessState != nil {
+				command.exitCode = command.cmd.ProcessState.ExitCode()
+			}
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		for _, command := range p.cmds {
+			if command.cmd.Process == nil {
+				continue
+			}
+			p.notifyShutdown(command, "kill")
+			syscall.Kill(-command.cmd.Process.Pid, syscall.SIGKILL)
+		}
+		<-done
+	}
+
+	for _, command := range p.cmds {
+		p.notifyShutdown(command, "stopped")
+	}
+
+	p.killed = true
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package processbuilder
+
+import (
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestShutdownPopulatesProcessState(t *testing.T) {
+	p, err := Create(
+		EmptyOption(),
+		NewCommand("sh", "-c", "trap 'exit 0' TERM; sleep 30"),
+	)
+	assert.NilError(t, err)
+	assert.NilError(t, Start(p))
+
+	err = Shutdown(p, GracefulOptions{Timeout: 2 * time.Second})
+	assert.NilError(t, err)
+
+	state := p.GetCmd(0).ProcessState
+	assert.Assert(t, state != nil, "ProcessState should be populated after Shutdown reaps the command")
+}
+
+// TestShutdownEscalatesToKillAndFiresHooks covers the headline behavior:
+// against a process that ignores SIGTERM entirely, Shutdown must still
+// escalate to SIGKILL once Timeout elapses, actually reap the process, and
+// drive OnShutdown through the "signal", "kill" and "stopped" phases.
+func TestShutdownEscalatesToKillAndFiresHooks(t *testing.T) {
+	p, err := Create(
+		EmptyOption(),
+		NewCommand("sh", "-c", "trap '' TERM; sleep 30"),
+	)
+	assert.NilError(t, err)
+
+	var mu sync.Mutex
+	var phases []string
+	p.OnShutdown(func(cmd *Command, phase string) {
+		mu.Lock()
+		phases = append(phases, phase)
+		mu.Unlock()
+	})
+
+	assert.NilError(t, Start(p))
+	pid := p.GetCmd(0).Process.Pid
+	time.Sleep(100 * time.Millisecond) // let the shell install its trap before signalling it
+
+	start := time.Now()
+	err = Shutdown(p, GracefulOptions{Timeout: 300 * time.Millisecond})
+	elapsed := time.Since(start)
+
+	assert.NilError(t, err)
+	assert.Assert(t, elapsed >= 300*time.Millisecond, "Shutdown returned before Timeout elapsed")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.DeepEqual(t, []string{"signal", "kill", "stopped"}, phases)
+
+	state := p.GetCmd(0).ProcessState
+	assert.Assert(t, state != nil)
+	assert.Assert(t, state.Sys().(syscall.WaitStatus).Signaled(), "process should have been reaped via SIGKILL")
+
+	// the process is gone: sending signal 0 to its pid should now fail
+	err = syscall.Kill(pid, 0)
+	assert.ErrorContains(t, err, "no such process")
+}
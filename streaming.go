@@ -0,0 +1,81 @@
+package processbuilder
+
+import (
+	"bufio"
+	"io"
+	"sync"
+)
+
+// Line is one line of stdout or stderr read from a pipeline stage, as
+// produced by Lines.
+type Line struct {
+	CmdIndex int
+	Stream   string
+	Text     string
+}
+
+// Lines attaches a tee to every stage's stdout and stderr (see Tee) and
+// streams their output line by line, on top of whatever the pipeline was
+// already configured to do with those streams - so the final grep|sed stage
+// can still write to disk while every intermediate stage's output is also
+// visible. It re-runs prepare(), so it must be called after Create/PipeOutput
+// and before Start. The tees are only flushed and closed once Run or Wait
+// reaps each stage, so callers must drain the returned Line channel
+// concurrently with calling Run/Wait (e.g. in a goroutine), not after -
+// otherwise the two block on each other.
+func Lines(p *Processbuilder) (<-chan Line, <-chan error) {
+	lines := make(chan Line)
+	errs := make(chan error, 1)
+
+	type stream struct {
+		r     io.Reader
+		index int
+		name  string
+	}
+
+	streams := make([]stream, 0, len(p.cmds)*2)
+
+	for index, command := range p.cmds {
+		outReader, outWriter := io.Pipe()
+		command.tees = append(command.tees, outWriter)
+		command.internalPipes = append(command.internalPipes, outWriter)
+		streams = append(streams, stream{outReader, index, "stdout"})
+
+		errReader, errWriter := io.Pipe()
+		if command.StdErr != nil {
+			command.StdErr = io.MultiWriter(command.StdErr, errWriter)
+		} else {
+			command.StdErr = errWriter
+		}
+		command.internalPipes = append(command.internalPipes, errWriter)
+		streams = append(streams, stream{errReader, index, "stderr"})
+	}
+
+	if _, err := p.prepare(); err != nil {
+		errs <- err
+		close(lines)
+		close(errs)
+		return lines, errs
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(streams))
+
+	for _, s := range streams {
+		go func(s stream) {
+			defer wg.Done()
+			scanner := bufio.NewScanner(s.r)
+			for scanner.Scan() {
+				lines <- Line{CmdIndex: s.index, Stream: s.name, Text: scanner.Text()}
+			}
+		}(s)
+	}
+
+	go func() {
+		wg.Wait()
+		close(lines)
+		close(errs)
+	}()
+
+	return lines, errs
+}
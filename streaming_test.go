@@ -0,0 +1,58 @@
+package processbuilder
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+// TestLinesOverPipeOutputDeliversStderr is a regression test: prepare()'s
+// last-stage stdoutPipe branch used to hard-code its own stderr pipe and
+// ignore command.StdErr entirely, so Lines()'s stderr tee - attached by
+// mutating command.StdErr - was silently never delivered for a PipeOutput
+// pipeline, even though the matching stdout tee worked fine.
+func TestLinesOverPipeOutputDeliversStderr(t *testing.T) {
+	p, err := PipeOutput(
+		EmptyOption(),
+		NewCommand("sh", "-c", "echo out-line; echo err-line 1>&2"),
+	)
+	assert.NilError(t, err)
+
+	lines, errs := Lines(p)
+
+	// Lines doesn't disturb PipeOutput's own StdoutPipe/StdErrPipe, so
+	// something still has to drain them.
+	go io.Copy(io.Discard, p.StdoutPipe)
+	go io.Copy(io.Discard, p.StdErrPipe)
+
+	var gotStdout, gotStderr bool
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for line := range lines {
+			switch {
+			case line.Stream == "stdout" && strings.Contains(line.Text, "out-line"):
+				gotStdout = true
+			case line.Stream == "stderr" && strings.Contains(line.Text, "err-line"):
+				gotStderr = true
+			}
+		}
+	}()
+
+	assert.NilError(t, Start(p))
+	_, _, err = Wait(p)
+	assert.NilError(t, err)
+
+	select {
+	case <-drained:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out draining Lines channel")
+	}
+
+	assert.NilError(t, <-errs)
+	assert.Assert(t, gotStdout, "expected a stdout line from Lines")
+	assert.Assert(t, gotStderr, "expected a stderr line from Lines over a PipeOutput pipeline")
+}
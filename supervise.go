@@ -0,0 +1,116 @@
+package processbuilder
+
+import "time"
+
+// SuperviseState is a state transition emitted on a Supervise's event channel.
+type SuperviseState string
+
+const (
+	SuperviseStateStarting SuperviseState = "Starting"
+	SuperviseStateRunning  SuperviseState = "Running"
+	SuperviseStateBackoff  SuperviseState = "Backoff"
+	SuperviseStateFatal    SuperviseState = "Fatal"
+	SuperviseStateStopped  SuperviseState = "Stopped"
+)
+
+// SuperviseEvent reports one state transition of a supervised pipeline.
+type SuperviseEvent struct {
+	State    SuperviseState
+	Attempt  int
+	ExitCode int
+	Uptime   time.Duration
+	Delay    time.Duration
+	Err      error
+}
+
+// SuperviseOptions configures Supervise.
+type SuperviseOptions struct {
+	// MaxRetries caps the total number of attempts, the first run included.
+	// Zero means unlimited.
+	MaxRetries int
+	// MinRunTime is the minimum uptime expected of the first attempt; if the
+	// pipeline exits before this elapses on attempt 1, Supervise treats it
+	// as a fatal misconfiguration and stops retrying.
+	MinRunTime time.Duration
+	// Backoff returns how long to wait before restart attempt n. A nil
+	// Backoff restarts immediately.
+	Backoff func(attempt int) time.Duration
+	// RestartOn decides whether a given exit should be retried. A nil
+	// RestartOn restarts on every non-nil exit.
+	RestartOn func(exitCode int, err error) bool
+}
+
+// Supervise runs p, restarting it with backoff whenever it exits, like a
+// process supervisor restarting a crashed daemon. Since a Processbuilder's
+// pipes and context are single-use, each attempt re-runs prepare() and
+// Start() against the same command definitions before waiting again. State
+// transitions (Starting, Running, Backoff, Fatal, Stopped) are emitted on
+// the returned channel, which is closed once the pipeline is no longer
+// retried.
+func Supervise(p *Processbuilder, opts SuperviseOptions) <-chan SuperviseEvent {
+	events := make(chan SuperviseEvent, 16)
+
+	go func() {
+		defer close(events)
+
+		attempt := 0
+
+		for {
+			attempt++
+			events <- SuperviseEvent{State: SuperviseStateStarting, Attempt: attempt}
+
+			if _, err := p.reset().prepare(); err != nil {
+				events <- SuperviseEvent{State: SuperviseStateFatal, Attempt: attempt, Err: err}
+				return
+			}
+
+			if err := Start(p); err != nil {
+				events <- SuperviseEvent{State: SuperviseStateFatal, Attempt: attempt, Err: err}
+				return
+			}
+
+			events <- SuperviseEvent{State: SuperviseStateRunning, Attempt: attempt}
+
+			startedAt := time.Now()
+			exitCode, _, err := Wait(p)
+			uptime := time.Since(startedAt)
+
+			if attempt == 1 && uptime < opts.MinRunTime {
+				events <- SuperviseEvent{State: SuperviseStateFatal, Attempt: attempt, ExitCode: exitCode, Uptime: uptime, Err: err}
+				return
+			}
+
+			restart := err != nil
+			if opts.RestartOn != nil {
+				restart = opts.RestartOn(exitCode, err)
+			}
+
+			if !restart || (opts.MaxRetries > 0 && attempt >= opts.MaxRetries) {
+				events <- SuperviseEvent{State: SuperviseStateStopped, Attempt: attempt, ExitCode: exitCode, Uptime: uptime, Err: err}
+				return
+			}
+
+			var delay time.Duration
+			if opts.Backoff != nil {
+				delay = opts.Backoff(attempt)
+			}
+
+			events <- SuperviseEvent{State: SuperviseStateBackoff, Attempt: attempt, ExitCode: exitCode, Uptime: uptime, Delay: delay, Err: err}
+
+			if delay > 0 {
+				time.Sleep(delay)
+			}
+		}
+	}()
+
+	return events
+}
+
+// reset clears the started/exited/killed flags so the pipeline's commands
+// can be rebuilt and run again via prepare()/Start().
+func (p *Processbuilder) reset() *Processbuilder {
+	p.started = false
+	p.exited = false
+	p.killed = false
+	return p
+}
@@ -0,0 +1,75 @@
+package processbuilder
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestSuperviseRetriesUntilMaxRetries(t *testing.T) {
+	p, err := Create(
+		EmptyOption(),
+		NewCommand("false"),
+	)
+	assert.NilError(t, err)
+
+	events := Supervise(p, SuperviseOptions{
+		MaxRetries: 3,
+		Backoff:    func(int) time.Duration { return 0 },
+	})
+
+	attempts := 0
+	var last SuperviseEvent
+	for ev := range events {
+		if ev.State == SuperviseStateStarting {
+			attempts++
+		}
+		last = ev
+	}
+
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, SuperviseStateStopped, last.State)
+}
+
+func TestSuperviseStopsImmediatelyWhenRestartOnRefuses(t *testing.T) {
+	p, err := Create(
+		EmptyOption(),
+		NewCommand("false"),
+	)
+	assert.NilError(t, err)
+
+	events := Supervise(p, SuperviseOptions{
+		MaxRetries: 10,
+		RestartOn:  func(exitCode int, err error) bool { return false },
+	})
+
+	attempts := 0
+	var last SuperviseEvent
+	for ev := range events {
+		if ev.State == SuperviseStateStarting {
+			attempts++
+		}
+		last = ev
+	}
+
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, SuperviseStateStopped, last.State)
+}
+
+func TestSuperviseTreatsImmediateExitBelowMinRunTimeAsFatal(t *testing.T) {
+	p, err := Create(
+		EmptyOption(),
+		NewCommand("true"),
+	)
+	assert.NilError(t, err)
+
+	events := Supervise(p, SuperviseOptions{MinRunTime: time.Hour})
+
+	var last SuperviseEvent
+	for ev := range events {
+		last = ev
+	}
+
+	assert.Equal(t, SuperviseStateFatal, last.State)
+}